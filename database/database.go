@@ -1,12 +1,11 @@
 package database
 
 import (
+	"bytes"
+	"container/heap"
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"io/fs"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -15,157 +14,423 @@ import (
 
 // Database is a generic in-memory KV store with optional per-key TTL and persistence.
 type Database[K comparable, V any] struct {
-	lock       sync.RWMutex
-	data       map[K]model.Entry[V]
+	lock sync.RWMutex
+	data map[K]model.Entry[V]
+	// dataShared is true when data may also be referenced by a live Snapshot
+	// and so must not be mutated in place. It is set by Snapshot and cleared
+	// by ensureOwnedLocked, which clones data the first time a mutation
+	// follows a Snapshot; every mutation after that clone writes straight
+	// into the clone until the next Snapshot call.
+	dataShared bool
 	defaultTTL time.Duration
 	basePath   string
+	storage    Storage
+	wal        *wal[K, V]
+
+	// Active TTL expiration: expiry is a min-heap of pending expirations
+	// guarded by lock; the goroutine started by startExpirer sleeps until
+	// the next one is due. nextGen hands out the generation stamped on
+	// every write so a popped heap entry can tell whether the key it names
+	// was since overwritten or deleted.
+	expiry      expiryHeap[K]
+	nextGen     uint64
+	expireWake  chan struct{}
+	expireStop  chan struct{}
+	expireDone  chan struct{}
+	expirations uint64
+	evictions   uint64
+	closeOnce   sync.Once
+
+	// replicaSeq is the highest WAL sequence number applied via Follow, so
+	// Follower can resume a dropped stream from where this database left off.
+	replicaSeq uint64
 }
 
-// NewDatabase creates a new database with an optional default TTL.
-// If defaultTTL <= 0, inserted keys won't expire unless SetWithTTL is used.
-// basePath: dir for store persist
-func NewDatabase[K comparable, V any](defaultTTL time.Duration, basePath string) *Database[K, V] {
-	return &Database[K, V]{
-		data:       make(map[K]model.Entry[V]),
-		defaultTTL: defaultTTL,
-		basePath:   basePath,
+// OpenWithWAL opens a database whose mutations are durable as soon as Set,
+// SetWithTTL, Delete or Clear return, without requiring an explicit Persist
+// call. On startup it replays the latest snapshot (written by Checkpoint)
+// plus any WAL records appended after it; a truncated or corrupt trailing
+// record, the signature of a crash mid-write, is discarded.
+func OpenWithWAL[K comparable, V any](basePath string, opts WALOptions) (*Database[K, V], error) {
+	storage := newFileStorage(basePath)
+	if err := storage.Open(); err != nil {
+		return nil, fmt.Errorf("open storage: %w", err)
 	}
+
+	db := &Database[K, V]{
+		data:     make(map[K]model.Entry[V]),
+		basePath: basePath,
+		storage:  storage,
+	}
+
+	snapSeq, err := db.recover(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := openWAL[K, V](basePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	// A Checkpoint may have truncated every record, leaving the snapshot's
+	// Seq as the only record of where numbering had reached.
+	if w.seq < snapSeq {
+		w.seq = snapSeq
+	}
+	db.wal = w
+
+	db.startExpirer()
+	return db, nil
 }
 
-// Set inserts or replaces the value for key, applying default TTL if configured.
-func (db *Database[K, V]) Set(key K, value V) {
+// recover loads the last snapshot (if any) and replays the WAL on top of it.
+// It returns the snapshot's Seq (0 if there is no snapshot).
+func (db *Database[K, V]) recover(basePath string) (uint64, error) {
+	var snapSeq uint64
+	switch data, err := db.storage.Load(snapshotFileName); {
+	case err == nil:
+		var p model.Persisted[K, V]
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&p); err != nil {
+			return 0, fmt.Errorf("decode snapshot: %w", err)
+		}
+		db.data = p.Data
+		db.defaultTTL = p.DefaultTTL
+		snapSeq = p.Seq
+	case errors.Is(err, ErrNotFound):
+		// No snapshot yet; replay starts from an empty map.
+	default:
+		return 0, fmt.Errorf("load snapshot: %w", err)
+	}
+
+	records, err := replayWAL[K, V](basePath)
+	if err != nil {
+		return 0, err
+	}
+	for _, rec := range records {
+		switch rec.Op {
+		case walOpSet:
+			db.data[rec.Key] = model.Entry[V]{Value: rec.Value, ExpiresAt: rec.ExpiresAt}
+		case walOpDelete:
+			delete(db.data, rec.Key)
+		case walOpClear:
+			db.data = make(map[K]model.Entry[V])
+		}
+	}
+
 	db.lock.Lock()
-	defer db.lock.Unlock()
+	db.rebuildExpiryHeapLocked()
+	db.lock.Unlock()
+	return snapSeq, nil
+}
 
+// Checkpoint writes a new snapshot via the existing atomic temp+rename
+// Persist path and then truncates the WAL, bounding replay time on the next
+// OpenWithWAL. It is only valid on a database opened with OpenWithWAL.
+//
+// The seq bound passed to truncatePrefix is read before Persist takes its
+// snapshot, not after: Persist's snapshot reflects every record up to
+// whatever seq the WAL is at by the time it takes its read lock, which can
+// only be greater than or equal to this earlier read. Truncating at the
+// earlier (lower) bound can at worst retain a few already-snapshotted
+// records, which recover() replays idempotently; truncating at a later bound
+// could discard a record that committed after Persist's snapshot but before
+// the truncate, losing a write that was already acked as durable.
+func (db *Database[K, V]) Checkpoint() error {
+	if db.wal == nil {
+		return errors.New("checkpoint: database was not opened with OpenWithWAL")
+	}
+	seq := db.wal.currentSeq()
+	if err := db.Persist(snapshotFileName); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := db.wal.truncatePrefix(seq); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Close stops the background expirer and releases resources held by the
+// database: the WAL file handle, if any, and the configured Storage backend.
+func (db *Database[K, V]) Close() error {
+	var err error
+	db.closeOnce.Do(func() {
+		close(db.expireStop)
+		<-db.expireDone
+
+		if db.wal != nil {
+			if e := db.wal.close(); e != nil {
+				err = e
+				return
+			}
+		}
+		if db.storage != nil {
+			err = db.storage.Close()
+		}
+	})
+	return err
+}
+
+// Set inserts or replaces the value for key, applying default TTL if configured.
+// If the database was opened with OpenWithWAL, it does not return until the
+// mutation has been durably logged.
+func (db *Database[K, V]) Set(key K, value V) error {
+	db.lock.Lock()
 	var exp time.Time
 	if db.defaultTTL > 0 {
 		exp = time.Now().Add(db.defaultTTL)
 	}
+	db.nextGen++
+	gen := db.nextGen
+
+	// The WAL write happens here, still under db.lock and before data is
+	// touched, rather than after unlocking: that keeps the order records
+	// land in the log (and so in replicas) identical to the order they're
+	// applied to memory, and it means a failed enqueue leaves memory
+	// untouched instead of reporting an error for a mutation that's already
+	// visible to Get.
+	var done <-chan error
+	if db.wal != nil {
+		var err error
+		done, err = db.wal.enqueue(walRecord[K, V]{Op: walOpSet, Key: key, Value: value, ExpiresAt: exp})
+		if err != nil {
+			db.lock.Unlock()
+			return err
+		}
+	}
+	db.ensureOwnedLocked()
+	db.data[key] = model.Entry[V]{Value: value, ExpiresAt: exp, Generation: gen}
+	if !exp.IsZero() {
+		heap.Push(&db.expiry, &expiryItem[K]{expiresAt: exp, key: key, generation: gen})
+	}
+	db.lock.Unlock()
 
-	db.data[key] = model.Entry[V]{Value: value, ExpiresAt: exp}
+	if !exp.IsZero() {
+		db.wakeExpirer()
+	}
+	if done == nil {
+		return nil
+	}
+	return <-done
 }
 
 // SetWithTTL inserts or replaces the value for key with a specific TTL.
-// If ttl <= 0, the value never expires (overrides defaultTTL).
-func (db *Database[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+// If ttl <= 0, the value never expires (overrides defaultTTL). If the
+// database was opened with OpenWithWAL, it does not return until the
+// mutation has been durably logged.
+func (db *Database[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
 	db.lock.Lock()
-	defer db.lock.Unlock()
-
 	var exp time.Time
 	if ttl > 0 {
 		exp = time.Now().Add(ttl)
 	}
+	db.nextGen++
+	gen := db.nextGen
+
+	// See Set for why the WAL write happens here, before data is touched,
+	// rather than after db.lock is released.
+	var done <-chan error
+	if db.wal != nil {
+		var err error
+		done, err = db.wal.enqueue(walRecord[K, V]{Op: walOpSet, Key: key, Value: value, ExpiresAt: exp})
+		if err != nil {
+			db.lock.Unlock()
+			return err
+		}
+	}
+	db.ensureOwnedLocked()
+	db.data[key] = model.Entry[V]{Value: value, ExpiresAt: exp, Generation: gen}
+	if !exp.IsZero() {
+		heap.Push(&db.expiry, &expiryItem[K]{expiresAt: exp, key: key, generation: gen})
+	}
+	db.lock.Unlock()
 
-	db.data[key] = model.Entry[V]{Value: value, ExpiresAt: exp}
+	if !exp.IsZero() {
+		db.wakeExpirer()
+	}
+	if done == nil {
+		return nil
+	}
+	return <-done
 }
 
-// Get returns the value for key. If the key is expired, it is removed and (zero, false) is returned.
+// Get returns the value for key. An expired key reads as absent even if the
+// background expirer hasn't evicted it yet.
 func (db *Database[K, V]) Get(key K) (V, bool) {
 	db.lock.RLock()
-	e, ok := db.data[key]
-	db.lock.RUnlock()
+	defer db.lock.RUnlock()
 
+	e, ok := db.data[key]
 	if !ok {
 		var zero V
 		return zero, false
 	}
-
-	// Fast path: not expired
-	if e.ExpiresAt.IsZero() || time.Now().Before(e.ExpiresAt) {
-		return e.Value, true
+	if !e.ExpiresAt.IsZero() && time.Now().After(e.ExpiresAt) {
+		var zero V
+		return zero, false
 	}
+	return e.Value, true
+}
 
-	// Expired: upgrade to write lock and delete
+// Delete removes a key if it exists. If the database was opened with
+// OpenWithWAL, it does not return until the mutation has been durably logged.
+func (db *Database[K, V]) Delete(key K) error {
 	db.lock.Lock()
-	defer db.lock.Unlock()
-	// Re-check in case of race
-	if e2, ok2 := db.data[key]; ok2 {
-		if !e2.ExpiresAt.IsZero() && time.Now().After(e2.ExpiresAt) {
-			delete(db.data, key)
-		} else {
-			return e2.Value, true
+	var done <-chan error
+	if db.wal != nil {
+		var err error
+		var zero V
+		done, err = db.wal.enqueue(walRecord[K, V]{Op: walOpDelete, Key: key, Value: zero})
+		if err != nil {
+			db.lock.Unlock()
+			return err
 		}
 	}
-	var zero V
-	return zero, false
-}
-
-// Delete removes a key if it exists.
-func (db *Database[K, V]) Delete(key K) {
-	db.lock.Lock()
-	defer db.lock.Unlock()
+	db.ensureOwnedLocked()
 	delete(db.data, key)
-}
+	db.lock.Unlock()
 
-// Clear removes all keys immediately.
-func (db *Database[K, V]) Clear() {
-	db.lock.Lock()
-	defer db.lock.Unlock()
-	db.data = make(map[K]model.Entry[V])
+	if done == nil {
+		return nil
+	}
+	return <-done
 }
 
-// CleanExpired removes all expired keys. Useful for periodic maintenance.
-func (db *Database[K, V]) CleanExpired() int {
+// Clear removes all keys immediately. If the database was opened with
+// OpenWithWAL, it does not return until the mutation has been durably logged.
+func (db *Database[K, V]) Clear() error {
 	db.lock.Lock()
-	defer db.lock.Unlock()
+	var done <-chan error
+	if db.wal != nil {
+		var err error
+		var zeroKey K
+		var zeroVal V
+		done, err = db.wal.enqueue(walRecord[K, V]{Op: walOpClear, Key: zeroKey, Value: zeroVal})
+		if err != nil {
+			db.lock.Unlock()
+			return err
+		}
+	}
+	db.data = make(map[K]model.Entry[V])
+	db.dataShared = false
+	db.expiry = nil
+	db.lock.Unlock()
 
-	now := time.Now()
+	if done == nil {
+		return nil
+	}
+	return <-done
+}
 
-	removed := 0
+// rebuildExpiryHeapLocked restamps every entry's generation and rebuilds the
+// expiry heap from scratch. Callers must hold db.lock for writing; used
+// after a bulk replace of db.data (WAL recovery, Load) where entries arrive
+// with generations that have no relation to db.nextGen.
+func (db *Database[K, V]) rebuildExpiryHeapLocked() {
+	h := make(expiryHeap[K], 0, len(db.data))
+	newData := make(map[K]model.Entry[V], len(db.data))
 	for k, e := range db.data {
-		if !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt) {
-			delete(db.data, k)
-			removed++
+		db.nextGen++
+		e.Generation = db.nextGen
+		newData[k] = e
+		if !e.ExpiresAt.IsZero() {
+			h = append(h, &expiryItem[K]{expiresAt: e.ExpiresAt, key: k, generation: e.Generation})
+		}
+	}
+	heap.Init(&h)
+	db.data = newData
+	db.dataShared = false
+	db.expiry = h
+}
+
+// applyWALRecord applies a single replicated mutation with the same
+// semantics as the local Set/Delete/Clear it was recorded from, and advances
+// replicaSeq so a dropped Follower stream can resume after it.
+func (db *Database[K, V]) applyWALRecord(rec walRecord[K, V]) {
+	db.lock.Lock()
+	switch rec.Op {
+	case walOpSet:
+		db.nextGen++
+		gen := db.nextGen
+		db.ensureOwnedLocked()
+		db.data[rec.Key] = model.Entry[V]{Value: rec.Value, ExpiresAt: rec.ExpiresAt, Generation: gen}
+		if !rec.ExpiresAt.IsZero() {
+			heap.Push(&db.expiry, &expiryItem[K]{expiresAt: rec.ExpiresAt, key: rec.Key, generation: gen})
 		}
+	case walOpDelete:
+		db.ensureOwnedLocked()
+		delete(db.data, rec.Key)
+	case walOpClear:
+		db.data = make(map[K]model.Entry[V])
+		db.dataShared = false
+		db.expiry = nil
+	}
+	db.replicaSeq = rec.Seq
+	scheduled := rec.Op == walOpSet && !rec.ExpiresAt.IsZero()
+	db.lock.Unlock()
+
+	if scheduled {
+		db.wakeExpirer()
 	}
+}
 
-	return removed
+// LastSeq returns the highest WAL sequence number this database has applied
+// via Follow.
+func (db *Database[K, V]) LastSeq() uint64 {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	return db.replicaSeq
+}
+
+// ensureOwnedLocked clones db.data the first time a mutation follows a
+// Snapshot, so the clone is paid at most once per Snapshot instead of on
+// every write: after Snapshot marks data shared, this call gives db.data a
+// private copy the snapshot can't see, and every mutation up to the next
+// Snapshot call writes straight into it. Callers must hold db.lock.
+func (db *Database[K, V]) ensureOwnedLocked() {
+	if !db.dataShared {
+		return
+	}
+	clone := make(map[K]model.Entry[V], len(db.data))
+	for k, v := range db.data {
+		clone[k] = v
+	}
+	db.data = clone
+	db.dataShared = false
 }
 
 // ******* Persist Methods *******
 
-// Persist writes the database atomically to filename (temp file + rename).
-// It captures a consistent snapshot under a read lock, then encodes outside the lock.
+// Persist writes the database to filename via the configured Storage.
+// It captures a consistent snapshot under a read lock, then encodes and
+// saves outside the lock. With the default file Storage this is an atomic
+// temp+rename of one file; with the bbolt or diskv Storage it is a single put
+// under filename. In every case the value written is the whole encoded
+// database: keys are not stored or updated individually.
 func (db *Database[K, V]) Persist(filename string) error {
 	// Take a snapshot under RLock to minimize blocking writers.
 	db.lock.RLock()
+	var seq uint64
+	if db.wal != nil {
+		seq = db.wal.currentSeq()
+	}
 	snap := model.Persisted[K, V]{
 		Version:    1,
 		DefaultTTL: db.defaultTTL,
 		Data:       make(map[K]model.Entry[V], len(db.data)),
+		Seq:        seq,
 	}
 	for k, v := range db.data {
 		snap.Data[k] = v
 	}
 	db.lock.RUnlock()
 
-	// Ensure directory exists.
-	path := db.getPath(filename)
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("ensure dir: %w", err)
-	}
-
-	tmp, err := os.CreateTemp(dir, ".db-*.gob.tmp")
-	if err != nil {
-		return fmt.Errorf("create temp: %w", err)
-	}
-
-	enc := gob.NewEncoder(tmp)
-	if err = enc.Encode(snap); err != nil {
-		_ = tmp.Close()
-		_ = os.Remove(tmp.Name())
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snap); err != nil {
 		return fmt.Errorf("encode gob: %w", err)
 	}
 
-	if err = tmp.Close(); err != nil {
-		_ = os.Remove(tmp.Name())
-		return fmt.Errorf("close temp: %w", err)
-	}
-
-	if err = os.Rename(tmp.Name(), path); err != nil {
-		_ = os.Remove(tmp.Name())
-		return fmt.Errorf("rename temp: %w", err)
+	if err := db.storage.Save(filename, buf.Bytes()); err != nil {
+		return fmt.Errorf("save %q: %w", filename, err)
 	}
 	return nil
 }
@@ -173,37 +438,31 @@ func (db *Database[K, V]) Persist(filename string) error {
 // Load replaces the in-memory state with the contents of filename.
 // It decodes into a temporary value first, then swaps under a write lock.
 func (db *Database[K, V]) Load(filename string) error {
-	path := db.getPath(filename)
-	f, err := os.Open(path)
+	data, err := db.storage.Load(filename)
 	if err != nil {
-		return err
+		return fmt.Errorf("load %q: %w", filename, err)
 	}
-	defer func() { _ = f.Close() }()
 
 	var p model.Persisted[K, V]
-	dec := gob.NewDecoder(f)
-	if err = dec.Decode(&p); err != nil {
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&p); err != nil {
 		return fmt.Errorf("decode gob: %w", err)
 	}
 
 	// Swap state under write lock.
 	db.lock.Lock()
 	db.data = p.Data
+	db.dataShared = false
 	db.defaultTTL = p.DefaultTTL
+	db.rebuildExpiryHeapLocked()
 	db.lock.Unlock()
 	return nil
 }
 
-// DeleteFile removes the persisted file. It is idempotent (no error if file is missing).
+// DeleteFile removes the persisted data for filename. It is idempotent
+// (no error if it is already missing).
 func (db *Database[K, V]) DeleteFile(filename string) error {
-	path := db.getPath(filename)
-	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return err
+	if err := db.storage.Delete(filename); err != nil {
+		return fmt.Errorf("delete %q: %w", filename, err)
 	}
 	return nil
 }
-
-func (db *Database[K, V]) getPath(fileName string) string {
-	path := filepath.Join(db.basePath, fileName)
-	return filepath.Clean(path)
-}
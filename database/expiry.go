@@ -0,0 +1,148 @@
+package database
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// expiryItem is a candidate expiration scheduled on a Database's heap.
+type expiryItem[K comparable] struct {
+	expiresAt  time.Time
+	key        K
+	generation uint64
+}
+
+// expiryHeap is a container/heap.Interface min-heap ordered by expiresAt.
+type expiryHeap[K comparable] []*expiryItem[K]
+
+func (h expiryHeap[K]) Len() int            { return len(h) }
+func (h expiryHeap[K]) Less(i, j int) bool  { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap[K]) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap[K]) Push(x interface{}) { *h = append(*h, x.(*expiryItem[K])) }
+func (h *expiryHeap[K]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// Stats reports the size of a Database and the activity of its background expirer.
+type Stats struct {
+	Size        int
+	HeapLen     int
+	Expirations uint64 // keys actively removed once their TTL elapsed
+	Evictions   uint64 // stale heap entries discarded (key overwritten/deleted before its scheduled expiry)
+}
+
+// Stats returns a point-in-time snapshot of db's size and expirer activity.
+func (db *Database[K, V]) Stats() Stats {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	return Stats{
+		Size:        len(db.data),
+		HeapLen:     len(db.expiry),
+		Expirations: atomic.LoadUint64(&db.expirations),
+		Evictions:   atomic.LoadUint64(&db.evictions),
+	}
+}
+
+// startExpirer launches the goroutine that actively evicts expired keys.
+// It must be called exactly once per Database.
+func (db *Database[K, V]) startExpirer() {
+	db.expireWake = make(chan struct{}, 1)
+	db.expireStop = make(chan struct{})
+	db.expireDone = make(chan struct{})
+	go db.runExpirer()
+}
+
+// runExpirer sleeps until the next scheduled expiration, evicts everything
+// due, and repeats. It wakes early whenever a sooner expiration is scheduled
+// or the heap goes from empty to non-empty, via expireWake.
+func (db *Database[K, V]) runExpirer() {
+	defer close(db.expireDone)
+
+	timer := time.NewTimer(time.Hour)
+	if !timer.Stop() {
+		<-timer.C
+	}
+
+	for {
+		db.lock.RLock()
+		var wait time.Duration
+		hasPending := len(db.expiry) > 0
+		if hasPending {
+			wait = time.Until(db.expiry[0].expiresAt)
+		}
+		db.lock.RUnlock()
+
+		if !hasPending {
+			select {
+			case <-db.expireWake:
+				continue
+			case <-db.expireStop:
+				return
+			}
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+			db.expireDue()
+		case <-db.expireWake:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-db.expireStop:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		}
+	}
+}
+
+// expireDue pops and deletes every heap entry whose expiresAt has passed.
+// An entry whose generation no longer matches the live one in db.data (the
+// key was overwritten or deleted since it was scheduled) is discarded
+// without deleting anything.
+func (db *Database[K, V]) expireDue() {
+	db.lock.Lock()
+	now := time.Now()
+	var expired []K
+	var stale int
+	for len(db.expiry) > 0 && !db.expiry[0].expiresAt.After(now) {
+		item := heap.Pop(&db.expiry).(*expiryItem[K])
+		e, ok := db.data[item.key]
+		if !ok || e.Generation != item.generation {
+			stale++
+			continue
+		}
+		expired = append(expired, item.key)
+	}
+	if len(expired) > 0 {
+		db.ensureOwnedLocked()
+		for _, k := range expired {
+			delete(db.data, k)
+		}
+	}
+	db.lock.Unlock()
+
+	if len(expired) > 0 {
+		atomic.AddUint64(&db.expirations, uint64(len(expired)))
+	}
+	if stale > 0 {
+		atomic.AddUint64(&db.evictions, uint64(stale))
+	}
+}
+
+// wakeExpirer nudges the expirer to recompute its sleep duration, e.g. after
+// scheduling an expiration that may now be the earliest one.
+func (db *Database[K, V]) wakeExpirer() {
+	select {
+	case db.expireWake <- struct{}{}:
+	default:
+	}
+}
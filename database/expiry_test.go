@@ -0,0 +1,62 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActiveExpiryEvictsDueKeys(t *testing.T) {
+	db, err := NewDatabase[string, int]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.SetWithTTL("a", 1, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if db.Stats().Expirations > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stats := db.Stats()
+	if stats.Expirations == 0 {
+		t.Fatal("expected the background expirer to have evicted the due key")
+	}
+	if stats.Size != 0 {
+		t.Fatalf("expected size 0 after eviction, got %d", stats.Size)
+	}
+}
+
+func TestOverwriteCancelsPendingExpiry(t *testing.T) {
+	db, err := NewDatabase[string, int]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.SetWithTTL("a", 1, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// Overwrite with no TTL before the original expiry fires: the stale heap
+	// item for the first write must be discarded by generation, not act on
+	// the key that replaced it.
+	if err := db.Set("a", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	v, ok := db.Get("a")
+	if !ok || v != 2 {
+		t.Fatalf("expected overwritten value 2 to survive, got %v, ok=%v", v, ok)
+	}
+	if db.Stats().Evictions == 0 {
+		t.Fatal("expected the stale heap entry to be counted as an eviction")
+	}
+}
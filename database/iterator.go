@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Serajian/cache-db/model"
+)
+
+// Snapshot is a point-in-time, read-only view of a Database. Capturing one
+// just marks the live map shared (see Database.ensureOwnedLocked) and holds
+// a reference to it; a write that lands after the Snapshot clones before it
+// mutates, so the Snapshot never sees it, and writes before the next
+// Snapshot are free to keep mutating that clone in place.
+type Snapshot[K comparable, V any] struct {
+	data map[K]model.Entry[V]
+	at   time.Time
+}
+
+// Snapshot captures the current state of db. The returned Snapshot is
+// unaffected by subsequent writes and must be released with Release once
+// the caller is done with it.
+func (db *Database[K, V]) Snapshot() *Snapshot[K, V] {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	db.dataShared = true
+	return &Snapshot[K, V]{data: db.data, at: time.Now()}
+}
+
+// Release drops the snapshot's reference to the underlying map.
+func (s *Snapshot[K, V]) Release() {
+	s.data = nil
+}
+
+// IterOptions configures an Iterator. Start and Limit are optional bounds on
+// the key range (Start inclusive, Limit exclusive); both require Less, since
+// K is only constrained to be comparable and otherwise has no defined order.
+type IterOptions[K comparable] struct {
+	Start *K
+	Limit *K
+	Less  func(a, b K) bool
+}
+
+// iterEntry pairs a key with its entry for sorting/filtering prior to iteration.
+type iterEntry[K comparable, V any] struct {
+	key   K
+	entry model.Entry[V]
+}
+
+// Iter walks a fixed, pre-filtered slice of entries captured at iterator
+// creation time; it is unaffected by writes that happen while it is in use.
+type Iter[K comparable, V any] struct {
+	entries []iterEntry[K, V]
+	pos     int
+	err     error
+}
+
+// Next advances the iterator and reports whether an entry is available.
+func (it *Iter[K, V]) Next() bool {
+	if it.err != nil || it.pos >= len(it.entries) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+// Key returns the key at the current position. Valid only after a true Next.
+func (it *Iter[K, V]) Key() K {
+	return it.entries[it.pos-1].key
+}
+
+// Value returns the value at the current position. Valid only after a true Next.
+func (it *Iter[K, V]) Value() V {
+	return it.entries[it.pos-1].entry.Value
+}
+
+// Err returns the first error encountered, if any (e.g. a bound given without Less).
+func (it *Iter[K, V]) Err() error {
+	return it.err
+}
+
+// Close releases the iterator's retained entries.
+func (it *Iter[K, V]) Close() error {
+	it.entries = nil
+	return nil
+}
+
+// Iterator returns an iterator over db's current state. Entries whose
+// ExpiresAt has passed relative to the snapshot time are skipped.
+func (db *Database[K, V]) Iterator(opts IterOptions[K]) *Iter[K, V] {
+	snap := db.Snapshot()
+	defer snap.Release()
+	return snap.Iterator(opts)
+}
+
+// Iterator returns an iterator over the snapshot's fixed view. Entries whose
+// ExpiresAt has passed relative to the snapshot time are skipped.
+func (s *Snapshot[K, V]) Iterator(opts IterOptions[K]) *Iter[K, V] {
+	if (opts.Start != nil || opts.Limit != nil) && opts.Less == nil {
+		return &Iter[K, V]{err: errors.New("iterator: Start/Limit bounds require Less")}
+	}
+
+	entries := make([]iterEntry[K, V], 0, len(s.data))
+	for k, e := range s.data {
+		if !e.ExpiresAt.IsZero() && s.at.After(e.ExpiresAt) {
+			continue
+		}
+		entries = append(entries, iterEntry[K, V]{key: k, entry: e})
+	}
+
+	if opts.Less != nil {
+		sort.Slice(entries, func(i, j int) bool { return opts.Less(entries[i].key, entries[j].key) })
+
+		if opts.Start != nil {
+			start := *opts.Start
+			i := sort.Search(len(entries), func(i int) bool { return !opts.Less(entries[i].key, start) })
+			entries = entries[i:]
+		}
+		if opts.Limit != nil {
+			limit := *opts.Limit
+			i := sort.Search(len(entries), func(i int) bool { return !opts.Less(entries[i].key, limit) })
+			entries = entries[:i]
+		}
+	}
+
+	return &Iter[K, V]{entries: entries}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Serajian/cache-db/model"
+)
+
+// Option configures a Database constructed via NewDatabase.
+type Option[K comparable, V any] func(*Database[K, V])
+
+// WithDefaultTTL sets the TTL applied to keys inserted via Set.
+// If ttl <= 0, inserted keys won't expire unless SetWithTTL is used.
+func WithDefaultTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return func(db *Database[K, V]) {
+		db.defaultTTL = ttl
+	}
+}
+
+// WithBasePath sets the directory the default file Storage persists into.
+// It has no effect if WithStorage is also given.
+func WithBasePath[K comparable, V any](basePath string) Option[K, V] {
+	return func(db *Database[K, V]) {
+		db.basePath = basePath
+	}
+}
+
+// WithStorage overrides the Storage backend used by Persist, Load and
+// DeleteFile. Without it, NewDatabase defaults to the atomic-file backend
+// rooted at basePath.
+func WithStorage[K comparable, V any](storage Storage) Option[K, V] {
+	return func(db *Database[K, V]) {
+		db.storage = storage
+	}
+}
+
+// NewDatabase creates a new database configured by opts. With no options it
+// is an in-memory store with no TTL and the default file Storage rooted at
+// the working directory. It returns an error if the Storage backend (the
+// default file Storage, or one supplied via WithStorage) fails to open.
+func NewDatabase[K comparable, V any](opts ...Option[K, V]) (*Database[K, V], error) {
+	db := &Database[K, V]{
+		data: make(map[K]model.Entry[V]),
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+	if db.storage == nil {
+		db.storage = newFileStorage(db.basePath)
+	}
+	if err := db.storage.Open(); err != nil {
+		return nil, fmt.Errorf("open storage: %w", err)
+	}
+	db.startExpirer()
+	return db, nil
+}
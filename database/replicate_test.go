@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReplicationRoundTripAcrossCheckpoint(t *testing.T) {
+	leaderDir := t.TempDir()
+	leader, err := OpenWithWAL[string, int](leaderDir, WALOptions{GracePeriod: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leader.Close()
+
+	srv := httptest.NewServer(leader.ReplicationHandler())
+	defer srv.Close()
+
+	followerDir := t.TempDir()
+	follower, err := OpenWithWAL[string, int](followerDir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = follower.Follower(ctx, srv.URL, FollowerOptions{ReconnectDelay: 50 * time.Millisecond})
+	}()
+
+	for i := 0; i < 5; i++ {
+		if err := leader.Set("k", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForValue(t, follower, "k", 4)
+
+	// A Checkpoint truncates the leader's WAL; the follower must resync by
+	// falling back to a snapshot instead of getting stuck on a now-invalid
+	// read offset.
+	if err := leader.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 5; i < 10; i++ {
+		if err := leader.Set("k", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForValue(t, follower, "k", 9)
+}
+
+func waitForValue(t *testing.T, db *Database[string, int], key string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if v, ok := db.Get(key); ok && v == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got, ok := db.Get(key)
+	t.Fatalf("timed out waiting for %q to become %d; last seen %v, ok=%v", key, want, got, ok)
+}
@@ -0,0 +1,26 @@
+package database
+
+import "strings"
+
+// PrefixScan returns an iterator over every non-expired entry of db whose key
+// has the given prefix, in key order. It is a standalone function rather than
+// a Database method because K is only constrained to be comparable elsewhere
+// in this package; prefix matching needs K to be string-like.
+func PrefixScan[K ~string, V any](db *Database[K, V], prefix K) *Iter[K, V] {
+	snap := db.Snapshot()
+	defer snap.Release()
+
+	less := func(a, b K) bool { return a < b }
+	it := snap.Iterator(IterOptions[K]{Less: less})
+	if it.Err() != nil {
+		return it
+	}
+
+	matched := make([]iterEntry[K, V], 0, len(it.entries))
+	for _, e := range it.entries {
+		if strings.HasPrefix(string(e.key), string(prefix)) {
+			matched = append(matched, e)
+		}
+	}
+	return &Iter[K, V]{entries: matched}
+}
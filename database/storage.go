@@ -0,0 +1,106 @@
+package database
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by Storage.Load when name has never been saved.
+var ErrNotFound = errors.New("storage: not found")
+
+// Storage persists named byte blobs on behalf of a Database. Implementations
+// choose their own durability/performance tradeoff: fileStorage (the
+// default) snapshots the whole database atomically on every Save, while the
+// bbolt and diskv backed drivers update a single name's data in place
+// without rewriting the rest of the store.
+type Storage interface {
+	// Open prepares the backend for use (e.g. creating directories, opening
+	// a handle). It is called once by NewDatabase.
+	Open() error
+	// Close releases any resources held by the backend.
+	Close() error
+	// Load returns the bytes saved under name, or ErrNotFound if absent.
+	Load(name string) ([]byte, error)
+	// Save stores data under name, replacing any previous value.
+	Save(name string, data []byte) error
+	// Delete removes name. It is idempotent: deleting a missing name is not an error.
+	Delete(name string) error
+}
+
+// fileStorage is the original atomic-file backend: each Save writes a temp
+// file under basePath and renames it into place.
+type fileStorage struct {
+	basePath string
+}
+
+// newFileStorage returns the default Storage, rooted at basePath.
+func newFileStorage(basePath string) *fileStorage {
+	return &fileStorage{basePath: basePath}
+}
+
+func (s *fileStorage) Open() error {
+	// An empty basePath means "rooted at the working directory" (see
+	// NewDatabase): os.MkdirAll rejects the empty string outright, so it
+	// needs the explicit "." to mean the same thing.
+	dir := s.basePath
+	if dir == "" {
+		dir = "."
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+func (s *fileStorage) Close() error {
+	return nil
+}
+
+func (s *fileStorage) Load(name string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *fileStorage) Save(name string, data []byte) error {
+	path := s.path(name)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".db-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *fileStorage) Delete(name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileStorage) path(name string) string {
+	return filepath.Clean(filepath.Join(s.basePath, name))
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bboltBucket is the single bucket all names are stored under.
+var bboltBucket = []byte("cache-db")
+
+// bboltStorage persists each name as its own key in a bbolt bucket, so
+// Save/Load/Delete touch only that key instead of rewriting the whole store.
+type bboltStorage struct {
+	path string
+	db   *bolt.DB
+}
+
+// NewBboltStorage opens (creating if necessary) a bbolt database file at
+// path for use as a Database's Storage, e.g. via WithStorage.
+func NewBboltStorage(path string) (Storage, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open bbolt: %w", err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bboltBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("create bucket: %w", err)
+	}
+	return &bboltStorage{path: path, db: db}, nil
+}
+
+// Open is a no-op: NewBboltStorage already opens the underlying file.
+func (s *bboltStorage) Open() error {
+	return nil
+}
+
+func (s *bboltStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *bboltStorage) Load(name string) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bboltBucket).Get([]byte(name))
+		if v == nil {
+			return ErrNotFound
+		}
+		data = append([]byte(nil), v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *bboltStorage) Save(name string, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bboltBucket).Put([]byte(name), data)
+	})
+}
+
+func (s *bboltStorage) Delete(name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bboltBucket).Delete([]byte(name))
+	})
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/peterbourgon/diskv/v3"
+)
+
+// diskvStorage spreads names across subdirectories via a user-supplied
+// Transform, with an in-memory cache of recently read/written values in
+// front of the disk (both provided by the diskv package itself). diskv's
+// cache is bounded by total byte size, not by recency: once CacheSizeMax is
+// reached it evicts in Go map iteration order, which is unspecified, not a
+// true least-recently-used policy.
+type diskvStorage struct {
+	d *diskv.Diskv
+}
+
+// NewDiskvStorage returns a Storage backed by a diskv sharded directory
+// store rooted at basePath. transform decides, for each name, the
+// subdirectory path diskv shards it under. cacheSizeBytes bounds the
+// in-memory cache of recently used values (see diskvStorage); 0 disables
+// caching.
+func NewDiskvStorage(basePath string, transform func(key string) []string, cacheSizeBytes uint64) Storage {
+	d := diskv.New(diskv.Options{
+		BasePath:     basePath,
+		Transform:    transform,
+		CacheSizeMax: cacheSizeBytes,
+	})
+	return &diskvStorage{d: d}
+}
+
+// Open is a no-op: diskv.New already prepares the store for use.
+func (s *diskvStorage) Open() error {
+	return nil
+}
+
+// Close is a no-op: diskv holds no handle that needs releasing.
+func (s *diskvStorage) Close() error {
+	return nil
+}
+
+func (s *diskvStorage) Load(name string) ([]byte, error) {
+	data, err := s.d.Read(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("diskv read: %w", err)
+	}
+	return data, nil
+}
+
+func (s *diskvStorage) Save(name string, data []byte) error {
+	if err := s.d.Write(name, data); err != nil {
+		return fmt.Errorf("diskv write: %w", err)
+	}
+	return nil
+}
+
+func (s *diskvStorage) Delete(name string) error {
+	if err := s.d.Erase(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("diskv erase: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayWALDiscardsCorruptTrailingRecord(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWAL[string, int](dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.append(walRecord[string, int]{Op: walOpSet, Key: "a", Value: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.append(walRecord[string, int]{Op: walOpSet, Key: "b", Value: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash mid-write: append a few garbage bytes that look like
+	// the start of a third record but are truncated.
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 5, 1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := replayWAL[string, int](dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 valid records, got %d", len(records))
+	}
+	if records[0].Key != "a" || records[1].Key != "b" {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+}
+
+func TestTruncatePrefixKeepsRecordsAfterSeq(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWAL[string, int](dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := w.append(walRecord[string, int]{Op: walOpSet, Value: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.truncatePrefix(3); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := replayWAL[string, int](dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records with seq > 3, got %d", len(records))
+	}
+	if records[0].Seq != 4 || records[1].Seq != 5 {
+		t.Fatalf("unexpected seqs: %+v", records)
+	}
+
+	// Seq stays monotonic across the truncation: the next append continues
+	// from 6, not from 1.
+	if err := w.append(walRecord[string, int]{Op: walOpSet, Value: 99}); err != nil {
+		t.Fatal(err)
+	}
+	records, err = replayWAL[string, int](dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[len(records)-1].Seq; got != 6 {
+		t.Fatalf("expected seq to continue at 6, got %d", got)
+	}
+}
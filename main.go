@@ -5,7 +5,7 @@ import (
 	"log"
 	"time"
 
-	"github.com/Serajian/cache-db.git/database"
+	"github.com/Serajian/cache-db/database"
 )
 
 func main() {
@@ -13,10 +13,18 @@ func main() {
 	basePath := "./data"
 
 	// ساخت DB با TTL پیش‌فرض 2 ثانیه
-	db := database.NewDatabase[string, string](2*time.Second, basePath)
+	db, err := database.NewDatabase[string, string](
+		database.WithDefaultTTL[string, string](2*time.Second),
+		database.WithBasePath[string, string](basePath),
+	)
+	if err != nil {
+		log.Fatal("new database failed:", err)
+	}
 
 	// Set با TTL پیش‌فرض
-	db.Set("foo", "bar")
+	if err := db.Set("foo", "bar"); err != nil {
+		log.Fatal("set failed:", err)
+	}
 
 	// ذخیره روی فایل
 	if err := db.Persist("test.gob"); err != nil {
@@ -25,7 +33,10 @@ func main() {
 	fmt.Println("✅ Persisted to", basePath+"/test.gob")
 
 	//ساخت DB جدید و Load از فایل
-	db2 := database.NewDatabase[string, string](0, basePath)
+	db2, err := database.NewDatabase[string, string](database.WithBasePath[string, string](basePath))
+	if err != nil {
+		log.Fatal("new database failed:", err)
+	}
 	if err := db2.Load("test.gob"); err != nil {
 		log.Fatal("load failed:", err)
 	}
@@ -4,15 +4,22 @@ import (
 	"time"
 )
 
-// Entry holds a value with an optional expiration timestamp.
+// Entry holds a value with an optional expiration timestamp. Generation is
+// bumped on every write to a key; it lets a background expirer tell a
+// scheduled expiration apart from a later overwrite of the same key.
 type Entry[V any] struct {
-	Value     V
-	ExpiresAt time.Time // zero means no expiration
+	Value      V
+	ExpiresAt  time.Time // zero means no expiration
+	Generation uint64
 }
 
 // Persisted is the on-disk format with a version for future migrations.
+// Seq is the WAL sequence number the snapshot was taken at (0 if the
+// database wasn't opened with a WAL); a replication follower bootstrapped
+// from this snapshot resumes streaming just after it.
 type Persisted[K comparable, V any] struct {
 	Version    int
 	DefaultTTL time.Duration
 	Data       map[K]Entry[V]
+	Seq        uint64
 }